@@ -0,0 +1,20 @@
+// Copyright (c) 2013 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+// SatoshiPerBitcoin is the number of satoshi in one bitcoin.
+const SatoshiPerBitcoin = 1e8
+
+// CentPerBitcoin is one hundredth of a bitcoin, used as the dust threshold
+// by the standard free transaction rule.
+const CentPerBitcoin = SatoshiPerBitcoin / 100
+
+// MaxSatoshi is the maximum transaction amount allowed in satoshi.
+const MaxSatoshi = 21e6 * SatoshiPerBitcoin
+
+// Amount represents the base bitcoin monetary unit (colloquially referred
+// to as a `satoshi`).  A single Amount is equal to 1 / 100 000 000 of a
+// bitcoin.
+type Amount int64
@@ -0,0 +1,116 @@
+// Copyright (c) 2013 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package chainhash provides a generic 32-byte hash type and the hashing
+// primitives built on top of it, decoupled from any single proof-of-work or
+// transaction hash algorithm. It exists so that forks of the packages built
+// on top of Hash can plug in an alternative algorithm (SHA-512/256, scrypt,
+// ...) without having to fork those packages too.
+package chainhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// HashSize is the number of bytes in the array used to represent a hash.
+const HashSize = 32
+
+// ErrHashStrSize describes an error that indicates the caller specified a
+// hash string that has an invalid length.
+var ErrHashStrSize = fmt.Errorf("max hash string length is %v bytes", HashSize*2)
+
+// Hash is used in several of the bitcoin messages and common structures.
+// It typically represents the double sha256 of data.
+type Hash [HashSize]byte
+
+// String returns the Hash as the hexadecimal string of the byte-reversed
+// hash, matching the convention used to display bitcoin block and
+// transaction hashes.
+func (h Hash) String() string {
+	for i := 0; i < HashSize/2; i++ {
+		h[i], h[HashSize-1-i] = h[HashSize-1-i], h[i]
+	}
+	return hex.EncodeToString(h[:])
+}
+
+// Bytes returns the bytes which represent the hash as a byte slice.
+func (h *Hash) Bytes() []byte {
+	newHash := make([]byte, HashSize)
+	copy(newHash, h[:])
+	return newHash
+}
+
+// SetBytes sets the bytes which represent the hash.  An error is returned if
+// the number of bytes passed in is not HashSize.
+func (h *Hash) SetBytes(newHash []byte) error {
+	if len(newHash) != HashSize {
+		return fmt.Errorf("invalid hash length of %v, want %v", len(newHash), HashSize)
+	}
+	copy(h[:], newHash)
+	return nil
+}
+
+// IsEqual returns true if target is the same as the hash.
+func (h *Hash) IsEqual(target *Hash) bool {
+	if h == nil && target == nil {
+		return true
+	}
+	if h == nil || target == nil {
+		return false
+	}
+	return *h == *target
+}
+
+// NewHash returns a new Hash from a byte slice.  An error is returned if the
+// number of bytes passed in is not HashSize.
+func NewHash(newHash []byte) (*Hash, error) {
+	var h Hash
+	if err := h.SetBytes(newHash); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// NewHashFromStr creates a Hash from a hash string.  The string should be
+// the hexadecimal string of a byte-reversed hash, but any missing
+// characters result in zero padding at the end of the Hash.
+func NewHashFromStr(hash string) (*Hash, error) {
+	if len(hash) > HashSize*2 {
+		return nil, ErrHashStrSize
+	}
+
+	srcBytes := []byte(hash)
+	if len(hash)%2 != 0 {
+		srcBytes = append([]byte("0"), srcBytes...)
+	}
+
+	var reversedHash Hash
+	if _, err := hex.Decode(reversedHash[HashSize-hex.DecodedLen(len(srcBytes)):], srcBytes); err != nil {
+		return nil, err
+	}
+	for i, j := 0, HashSize-1; i < j; i, j = i+1, j-1 {
+		reversedHash[i], reversedHash[j] = reversedHash[j], reversedHash[i]
+	}
+	return &reversedHash, nil
+}
+
+// Hasher computes a Hash from arbitrary data.  It decouples Hash, and the
+// packages built on top of it, from any single hash algorithm, so that a
+// fork can supply its own implementation (SHA-512/256, scrypt, ...) without
+// forking those packages too.
+type Hasher interface {
+	Hash(data []byte) Hash
+}
+
+// DoubleSHA256Hasher implements Hasher using bitcoin's standard double
+// SHA-256, and is the default used throughout this module.
+type DoubleSHA256Hasher struct{}
+
+// Hash returns the double SHA-256 of data.
+func (DoubleSHA256Hasher) Hash(data []byte) Hash {
+	first := sha256.Sum256(data)
+	return Hash(sha256.Sum256(first[:]))
+}
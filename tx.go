@@ -6,9 +6,23 @@ package btcutil
 
 import (
 	"bytes"
-	"github.com/conformal/btcwire"
+	"errors"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/conformal/btcutil/chainhash"
 )
 
+// hasher computes the hash used to identify a transaction.  It defaults to
+// bitcoin's double SHA-256 but is declared as a chainhash.Hasher so forks
+// that use a different algorithm can swap it out without forking this
+// package.
+var hasher chainhash.Hasher = chainhash.DoubleSHA256Hasher{}
+
+// errNoSerializedTx is returned internally by stripWitness when a Tx has no
+// cached serialized bytes to strip the witness section from.
+var errNoSerializedTx = errors.New("btcutil: no serialized bytes cached for transaction")
+
 // TxIndexUnknown is the value returned for a transaction index that is unknown.
 // This is typically because the transaction has not been inserted into a block
 // yet.
@@ -19,36 +33,177 @@ const TxIndexUnknown = -1
 // transaction on its first access so subsequent accesses don't have to repeat
 // the relatively expensive hashing operations.
 type Tx struct {
-	msgTx        *btcwire.MsgTx   // Underlying MsgTx
-	serializedTx []byte           // Serialized bytes for the transaction
-	txSha        *btcwire.ShaHash // Cached transaction hash
-	txIndex      int              // Position within a block or TxIndexUnknown
+	msgTx         *wire.MsgTx     // Underlying MsgTx
+	serializedTx  []byte          // Serialized bytes for the transaction
+	txSha         *chainhash.Hash // Cached transaction hash (txid)
+	txWitnessHash *chainhash.Hash // Cached witness transaction hash (wtxid)
+	txIndex       int             // Position within a block or TxIndexUnknown
 }
 
-// MsgTx returns the underlying btcwire.MsgTx for the transaction.
-func (t *Tx) MsgTx() *btcwire.MsgTx {
+// MsgTx returns the underlying wire.MsgTx for the transaction.
+func (t *Tx) MsgTx() *wire.MsgTx {
 	// Return the cached transaction.
 	return t.msgTx
 }
 
-// Sha returns the hash of the transaction.  This is equivalent to
-// calling TxSha on the underlying btcwire.MsgTx, however it caches the
-// result so subsequent calls are more efficient.
-func (t *Tx) Sha() *btcwire.ShaHash {
+// Hash returns the hash of the transaction, ignoring witness data as
+// defined by BIP 141 (the "txid").  The hash is cached on first access so
+// subsequent calls are more efficient.
+//
+// When the serialized bytes for the transaction are already known (for
+// example, because the Tx was created via NewTxFromBytes), the witness
+// section, if any, is stripped directly from that cached buffer rather than
+// paying to re-serialize msgTx through MsgTx.SerializeNoWitness.
+func (t *Tx) Hash() *chainhash.Hash {
 	// Return the cached hash if it has already been generated.
 	if t.txSha != nil {
 		return t.txSha
 	}
 
-	// Generate the transaction hash.  Ignore the error since TxSha can't
-	// currently fail.
-	sha, _ := t.msgTx.TxSha()
+	legacy, err := stripWitness(t.serializedTx)
+	if err != nil {
+		var buf bytes.Buffer
+		// Ignore the error; SerializeNoWitness can't fail when
+		// writing into an in-memory buffer.
+		t.msgTx.SerializeNoWitness(&buf)
+		legacy = buf.Bytes()
+	}
+	sha := hasher.Hash(legacy)
 
 	// Cache the hash and return it.
 	t.txSha = &sha
 	return &sha
 }
 
+// Sha returns the hash of the transaction.
+//
+// Deprecated: use Hash instead.  Sha will be removed in a future release.
+func (t *Tx) Sha() *chainhash.Hash {
+	return t.Hash()
+}
+
+// TxHash is an alias for Hash, named to match the method wire.MsgTx itself
+// uses for the same (witness-stripping) hash.
+func (t *Tx) TxHash() *chainhash.Hash {
+	return t.Hash()
+}
+
+// TxSha returns the hash of the transaction.
+//
+// Deprecated: use TxHash instead.  TxSha will be removed in a future release.
+func (t *Tx) TxSha() *chainhash.Hash {
+	return t.Hash()
+}
+
+// WitnessHash returns the hash of the transaction including witness data, as
+// defined by BIP 141 (the "wtxid").  It is cached independently of Hash so
+// that callers needing only one of the two values never pay for the other.
+// For a transaction with no witness data, WitnessHash and Hash are equal.
+func (t *Tx) WitnessHash() *chainhash.Hash {
+	// Return the cached hash if it has already been generated.
+	if t.txWitnessHash != nil {
+		return t.txWitnessHash
+	}
+
+	var wsha chainhash.Hash
+	if t.serializedTx != nil {
+		wsha = hasher.Hash(t.serializedTx)
+	} else {
+		var buf bytes.Buffer
+		// Ignore the error; Serialize can't fail when writing into
+		// an in-memory buffer.
+		t.msgTx.Serialize(&buf)
+		wsha = hasher.Hash(buf.Bytes())
+	}
+
+	// Cache the hash and return it.
+	t.txWitnessHash = &wsha
+	return &wsha
+}
+
+// HasWitness reports whether any input of the transaction carries witness
+// data.
+func (t *Tx) HasWitness() bool {
+	for _, txIn := range t.msgTx.TxIn {
+		if len(txIn.Witness) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// BaseSize returns the serialized size of the transaction excluding any
+// witness data, as defined by BIP 141.
+func (t *Tx) BaseSize() int {
+	if !t.HasWitness() {
+		return t.SerializeSize()
+	}
+
+	var buf bytes.Buffer
+	// Ignore the error; SerializeNoWitness can't fail when writing into
+	// an in-memory buffer.
+	t.msgTx.SerializeNoWitness(&buf)
+	return buf.Len()
+}
+
+// Weight returns the transaction weight as defined by BIP 141:
+// base_size*3 + total_size.
+func (t *Tx) Weight() int64 {
+	return int64(t.BaseSize()*3 + t.SerializeSize())
+}
+
+// VirtualSize returns the virtual transaction size as defined by BIP 141:
+// (weight + 3) / 4.  For a transaction with no witness data this is equal
+// to SerializeSize.
+func (t *Tx) VirtualSize() int {
+	return int((t.Weight() + 3) / 4)
+}
+
+// Bytes returns the serialized bytes for the transaction, computed from the
+// cached buffer populated by NewTxFromBytes when available, and otherwise
+// by serializing msgTx and memoizing the result so later calls don't pay to
+// serialize again. The returned slice is a copy; callers are free to mutate
+// it without corrupting the Tx's cached bytes, size, or hashes.
+func (t *Tx) Bytes() ([]byte, error) {
+	if t.serializedTx == nil {
+		var buf bytes.Buffer
+		if err := t.msgTx.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		t.serializedTx = buf.Bytes()
+	}
+
+	serializedTx := make([]byte, len(t.serializedTx))
+	copy(serializedTx, t.serializedTx)
+	return serializedTx, nil
+}
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// transaction, preferring the cached length populated by Bytes or
+// NewTxFromBytes over calling into msgTx.SerializeSize().
+func (t *Tx) SerializeSize() int {
+	if t.serializedTx != nil {
+		return len(t.serializedTx)
+	}
+	return t.msgTx.SerializeSize()
+}
+
+// OutputAmount returns the sum of all output values in the transaction.  If
+// ignoreChange is true, the final output — conventionally the change output
+// appended by a TxBuilder — is excluded from the total.
+func (t *Tx) OutputAmount(ignoreChange bool) Amount {
+	outs := t.msgTx.TxOut
+	if ignoreChange && len(outs) > 0 {
+		outs = outs[:len(outs)-1]
+	}
+
+	var total Amount
+	for _, out := range outs {
+		total += Amount(out.Value)
+	}
+	return total
+}
+
 // Index returns the saved index of the transaction within a block.  This value
 // will be TxIndexUnknown if it hasn't already explicitly been set.
 func (t *Tx) Index() int {
@@ -61,8 +216,8 @@ func (t *Tx) SetIndex(index int) {
 }
 
 // NewTx returns a new instance of a bitcoin transaction given an underlying
-// btcwire.MsgTx.  See Tx.
-func NewTx(msgTx *btcwire.MsgTx) *Tx {
+// wire.MsgTx.  See Tx.
+func NewTx(msgTx *wire.MsgTx) *Tx {
 	return &Tx{
 		msgTx:   msgTx,
 		txIndex: TxIndexUnknown,
@@ -73,7 +228,7 @@ func NewTx(msgTx *btcwire.MsgTx) *Tx {
 // serialized bytes.  See Tx.
 func NewTxFromBytes(serializedTx []byte) (*Tx, error) {
 	// Deserialize the bytes into a MsgTx.
-	var msgTx btcwire.MsgTx
+	var msgTx wire.MsgTx
 	br := bytes.NewBuffer(serializedTx)
 	err := msgTx.Deserialize(br)
 	if err != nil {
@@ -86,4 +241,178 @@ func NewTxFromBytes(serializedTx []byte) (*Tx, error) {
 		txIndex:      TxIndexUnknown,
 	}
 	return &t, nil
+}
+
+// stripWitness returns the legacy (non-witness) wire encoding of a
+// transaction given its full serialized bytes, working directly on raw
+// instead of re-serializing the transaction's parsed MsgTx. If raw is nil,
+// errNoSerializedTx is returned. If raw carries no BIP 141/144 marker, it is
+// already in legacy form and is returned unmodified.
+func stripWitness(raw []byte) ([]byte, error) {
+	if raw == nil {
+		return nil, errNoSerializedTx
+	}
+	if len(raw) < 6 || raw[4] != 0x00 || raw[5] != 0x01 {
+		return raw, nil
+	}
+
+	r := bytes.NewReader(raw)
+	var legacy bytes.Buffer
+
+	var version [4]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return nil, err
+	}
+	legacy.Write(version[:])
+
+	// Skip the marker and flag bytes; they have no place in the legacy
+	// encoding.
+	if _, err := r.Seek(2, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	txInCount, err := copyVarInt(&legacy, r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < txInCount; i++ {
+		if err := copyTxIn(&legacy, r); err != nil {
+			return nil, err
+		}
+	}
+
+	txOutCount, err := copyVarInt(&legacy, r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < txOutCount; i++ {
+		if err := copyTxOut(&legacy, r); err != nil {
+			return nil, err
+		}
+	}
+
+	// Skip the witness stack for each input; none of it belongs in the
+	// legacy encoding.
+	for i := uint64(0); i < txInCount; i++ {
+		stackLen, err := readVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < stackLen; j++ {
+			itemLen, err := readVarInt(r)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := r.Seek(int64(itemLen), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var locktime [4]byte
+	if _, err := io.ReadFull(r, locktime[:]); err != nil {
+		return nil, err
+	}
+	legacy.Write(locktime[:])
+
+	return legacy.Bytes(), nil
+}
+
+// copyTxIn copies one wire-format transaction input (outpoint, signature
+// script, and sequence) from r to w.
+func copyTxIn(w *bytes.Buffer, r *bytes.Reader) error {
+	var outpoint [36]byte
+	if _, err := io.ReadFull(r, outpoint[:]); err != nil {
+		return err
+	}
+	w.Write(outpoint[:])
+
+	if err := copyVarBytes(w, r); err != nil {
+		return err
+	}
+
+	var sequence [4]byte
+	if _, err := io.ReadFull(r, sequence[:]); err != nil {
+		return err
+	}
+	w.Write(sequence[:])
+	return nil
+}
+
+// copyTxOut copies one wire-format transaction output (value and public key
+// script) from r to w.
+func copyTxOut(w *bytes.Buffer, r *bytes.Reader) error {
+	var value [8]byte
+	if _, err := io.ReadFull(r, value[:]); err != nil {
+		return err
+	}
+	w.Write(value[:])
+
+	return copyVarBytes(w, r)
+}
+
+// copyVarBytes copies a CompactSize-prefixed byte string from r to w.
+func copyVarBytes(w *bytes.Buffer, r *bytes.Reader) error {
+	n, err := copyVarInt(w, r)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	w.Write(buf)
+	return nil
+}
+
+// copyVarInt reads a CompactSize-encoded integer from r, copies its exact
+// encoding to w, and returns the decoded value.
+func copyVarInt(w *bytes.Buffer, r *bytes.Reader) (uint64, error) {
+	before := r.Len()
+	v, err := readVarInt(r)
+	if err != nil {
+		return 0, err
+	}
+
+	consumed := before - r.Len()
+	if _, err := r.Seek(int64(-consumed), io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	raw := make([]byte, consumed)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return 0, err
+	}
+	w.Write(raw)
+	return v, nil
+}
+
+// readVarInt reads a bitcoin CompactSize-encoded integer from r.
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	discriminant, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int
+	switch discriminant {
+	case 0xff:
+		size = 8
+	case 0xfe:
+		size = 4
+	case 0xfd:
+		size = 2
+	default:
+		return uint64(discriminant), nil
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(buf[i])
+	}
+	return v, nil
 }
\ No newline at end of file
@@ -0,0 +1,136 @@
+// Copyright (c) 2013 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestHashMatchesWitnessHashWithoutWitness(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	if *tx.Hash() != *tx.WitnessHash() {
+		t.Fatal("Hash and WitnessHash should be equal for a transaction with no witness data")
+	}
+	if *tx.Hash() != *tx.Sha() {
+		t.Fatal("the deprecated Sha shim should return the same hash as Hash")
+	}
+	if *tx.Hash() != *tx.TxHash() {
+		t.Fatal("TxHash should return the same hash as Hash")
+	}
+	if *tx.Hash() != *tx.TxSha() {
+		t.Fatal("the deprecated TxSha shim should return the same hash as Hash")
+	}
+}
+
+func TestWitnessHashDiffersWithWitness(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	txIn := wire.NewTxIn(&wire.OutPoint{}, nil, nil)
+	txIn.Witness = wire.TxWitness{[]byte{0x01}}
+	msgTx.AddTxIn(txIn)
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	if !tx.HasWitness() {
+		t.Fatal("HasWitness should report true for a transaction with witness data")
+	}
+	if *tx.Hash() == *tx.WitnessHash() {
+		t.Fatal("Hash and WitnessHash should differ when the transaction carries witness data")
+	}
+}
+
+func TestHashReusesCachedBytesForWitnessTx(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	txIn := wire.NewTxIn(&wire.OutPoint{}, nil, nil)
+	txIn.Witness = wire.TxWitness{[]byte{0x01}}
+	msgTx.AddTxIn(txIn)
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+
+	var buf []byte
+	{
+		tmp := NewTx(msgTx)
+		var err error
+		buf, err = tmp.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() error = %v", err)
+		}
+	}
+
+	fromBytes, err := NewTxFromBytes(buf)
+	if err != nil {
+		t.Fatalf("NewTxFromBytes() error = %v", err)
+	}
+	fromMsgTx := NewTx(msgTx)
+
+	if *fromBytes.Hash() != *fromMsgTx.Hash() {
+		t.Fatal("Hash() computed from cached bytes should match Hash() computed from MsgTx")
+	}
+}
+
+func TestBytesReturnsACopy(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	b, err := tx.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	b[0] ^= 0xff
+
+	sizeBefore := tx.SerializeSize()
+	b2, err := tx.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if b2[0] == b[0] {
+		t.Fatal("mutating a previously returned Bytes() slice corrupted the Tx's cached bytes")
+	}
+	if tx.SerializeSize() != sizeBefore {
+		t.Fatal("SerializeSize() changed after mutating a Bytes() result")
+	}
+}
+
+func TestSizeWeightVirtualSize(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	base := tx.BaseSize()
+	total := tx.SerializeSize()
+	if base != total {
+		t.Fatalf("BaseSize() = %d, want %d (equal to SerializeSize for a non-witness tx)", base, total)
+	}
+
+	wantWeight := int64(base*3 + total)
+	if got := tx.Weight(); got != wantWeight {
+		t.Fatalf("Weight() = %d, want %d", got, wantWeight)
+	}
+
+	wantVSize := int((wantWeight + 3) / 4)
+	if got := tx.VirtualSize(); got != wantVSize {
+		t.Fatalf("VirtualSize() = %d, want %d", got, wantVSize)
+	}
+}
+
+func TestOutputAmount(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	msgTx.AddTxOut(wire.NewTxOut(2000, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	if got := tx.OutputAmount(false); got != 3000 {
+		t.Fatalf("OutputAmount(false) = %d, want 3000", got)
+	}
+	if got := tx.OutputAmount(true); got != 1000 {
+		t.Fatalf("OutputAmount(true) = %d, want 1000", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// fakeUTXOSource is a fixed set of UTXOs used to drive TxBuilder tests
+// without depending on a wallet or chain backend.
+type fakeUTXOSource []*UTXO
+
+func (s fakeUTXOSource) UTXOs() ([]*UTXO, error) {
+	return []*UTXO(s), nil
+}
+
+func TestMinimumFeeFormula(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(int64(CentPerBitcoin), []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	txLen := Amount(tx.SerializeSize())
+	want := (1 + txLen/1000) * FeeIncrement
+	if got := MinimumFee(tx, false); got != want {
+		t.Fatalf("MinimumFee() = %d, want %d", got, want)
+	}
+}
+
+func TestMinimumFeeFreeTransaction(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(int64(CentPerBitcoin), []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	if got := MinimumFee(tx, true); got != 0 {
+		t.Fatalf("MinimumFee() with allowFree = %d, want 0", got)
+	}
+}
+
+func TestMinimumFeeDustForcesFee(t *testing.T) {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(int64(CentPerBitcoin)-1, []byte{0x51}))
+	tx := NewTx(msgTx)
+
+	if got := MinimumFee(tx, true); got == 0 {
+		t.Fatal("MinimumFee() with a dust output should not be exempted by the free-tx rule")
+	}
+}
+
+func TestTxBuilderSelectInputsFoldsFee(t *testing.T) {
+	source := fakeUTXOSource{
+		{OutPoint: wire.OutPoint{Index: 0}, Amount: 50000},
+		{OutPoint: wire.OutPoint{Index: 1}, Amount: 50000},
+	}
+	builder := NewTxBuilder(source)
+	builder.AddOutput([]byte{0x51}, 90000)
+
+	change, err := builder.SelectInputs(90000)
+	if err != nil {
+		t.Fatalf("SelectInputs() error = %v", err)
+	}
+
+	tx := builder.Build()
+	fee := builder.MinimumFee(tx)
+	if want := Amount(100000) - 90000 - fee; change != want {
+		t.Fatalf("SelectInputs() change = %d, want %d (fee %d not folded in)", change, want, fee)
+	}
+	if len(tx.MsgTx().TxIn) != 2 {
+		t.Fatalf("SelectInputs() selected %d inputs, want 2", len(tx.MsgTx().TxIn))
+	}
+}
+
+func TestTxBuilderSelectInputsInsufficientFunds(t *testing.T) {
+	source := fakeUTXOSource{
+		{OutPoint: wire.OutPoint{Index: 0}, Amount: 1000},
+	}
+	builder := NewTxBuilder(source)
+	builder.AddOutput([]byte{0x51}, 90000)
+
+	if _, err := builder.SelectInputs(90000); err != ErrInsufficientFunds {
+		t.Fatalf("SelectInputs() error = %v, want ErrInsufficientFunds", err)
+	}
+}